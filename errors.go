@@ -1,9 +1,24 @@
 package fasttemplate
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrEmptyStartTag = errors.New("startTag cannot be empty")
-	ErrEmptyEndTag = errors.New("endTag cannot be empty")
-	ErrInvalidTag = errors.New("tag contains unexpected value type. Expected []byte, string or TagFunc")
+	ErrEmptyStartTag    = errors.New("startTag cannot be empty")
+	ErrEmptyEndTag      = errors.New("endTag cannot be empty")
+	ErrInvalidTag       = errors.New("tag contains unexpected value type. Expected []byte, string or TagFunc")
+	ErrUnknownFormatter = errors.New("tag references an unregistered formatter name")
 )
+
+// CycleError is returned by TemplateSet.Parse (or, for cycles spanning a
+// lazily-loaded template, TemplateSet.Execute) when a template's "extends"
+// or "include" directives loop back on themselves.
+type CycleError struct {
+	Name string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("fasttemplate: cycle detected at template %q", e.Name)
+}
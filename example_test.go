@@ -23,18 +23,48 @@ func ExampleTemplate() {
 
 		// TagFunc - flexible value. TagFunc is called only if the given
 		// tag exists in the template.
-		"query": TagFunc(func(w io.Writer) (int, error) {
+		"query": TagFunc(func(w io.Writer, tag string) (int, error) {
 			return w.Write([]byte(url.QueryEscape("hello=world")))
 		}),
 	}
 
-	s := t.ExecuteString(m)
+	s, err := t.ExecuteString(m)
+	if err != nil {
+		log.Fatalf("unexpected error when executing template: %s", err)
+	}
 	fmt.Printf("%s", s)
 
 	// Output:
 	// http://google.com/?foo=foobarfoobar&q=hello%3Dworld&baz=
 }
 
+func ExampleTemplate_formatters() {
+	template := "hello, {{name|html|upper}}!"
+	t, err := NewTemplate(template, "{{", "}}")
+	if err != nil {
+		log.Fatalf("unexpected error when parsing template: %s", err)
+	}
+
+	// Pipe-separated formatter chains are opt-in, so tags whose names
+	// literally contain "|" keep working until this is called.
+	if err := t.EnablePipeSeparator(DefaultPipeSeparator); err != nil {
+		log.Fatalf("unexpected error when enabling formatters: %s", err)
+	}
+
+	m := map[string]interface{}{
+		"name": []byte("<world>"),
+	}
+
+	s, err := t.ExecuteString(m)
+	if err != nil {
+		log.Fatalf("unexpected error when executing template: %s", err)
+	}
+	fmt.Printf("%s", s)
+
+	// Output:
+	// hello, &LT;WORLD&GT;!
+}
+
 func ExampleTagFunc() {
 	template := "foo[baz]bar"
 	t, err := NewTemplate(template, "[", "]")
@@ -47,7 +77,7 @@ func ExampleTagFunc() {
 		// Always wrap the function into TagFunc.
 		//
 		// "baz" tag function writes bazSlice contents into w.
-		"baz": TagFunc(func(w io.Writer) (int, error) {
+		"baz": TagFunc(func(w io.Writer, tag string) (int, error) {
 			var nn int
 			for _, x := range bazSlice {
 				n, err := w.Write(x)
@@ -60,7 +90,10 @@ func ExampleTagFunc() {
 		}),
 	}
 
-	s := t.ExecuteString(m)
+	s, err := t.ExecuteString(m)
+	if err != nil {
+		log.Fatalf("unexpected error when executing template: %s", err)
+	}
 	fmt.Printf("%s", s)
 
 	// Output:
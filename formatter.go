@@ -0,0 +1,159 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// DefaultPipeSeparator is the conventional separator byte to pass to
+// EnablePipeSeparator for chaining formatters onto a tag name, e.g.
+// "ref|url". EnablePipeSeparator has no implicit default: passing 0 leaves
+// pipe syntax disabled.
+const DefaultPipeSeparator = '|'
+
+// Formatter transforms the bytes in in and writes the result to w.
+//
+// Formatters are applied in the order they are chained onto a tag, e.g.
+// "{{body|html|trim}}" runs the tag's resolved value through the "html"
+// formatter and then through "trim" before writing it to the output.
+type Formatter func(w io.Writer, in []byte) (int, error)
+
+// FormatterMap maps formatter names, as referenced in a tag's pipe chain,
+// to the Formatter implementing them.
+type FormatterMap map[string]Formatter
+
+// DefaultFormatters is the FormatterMap used to resolve a tag's formatter
+// chain when pipe syntax is enabled via EnablePipeSeparator and
+// Template.SetFormatters hasn't been called.
+var DefaultFormatters = FormatterMap{
+	"html":    formatHTML,
+	"url":     formatURL,
+	"jsonstr": formatJSONString,
+	"base64":  formatBase64,
+	"trim":    formatTrim,
+	"upper":   formatUpper,
+	"lower":   formatLower,
+}
+
+func formatHTML(w io.Writer, in []byte) (int, error) {
+	return io.WriteString(w, html.EscapeString(unsafeBytes2String(in)))
+}
+
+func formatURL(w io.Writer, in []byte) (int, error) {
+	return io.WriteString(w, url.QueryEscape(unsafeBytes2String(in)))
+}
+
+func formatJSONString(w io.Writer, in []byte) (int, error) {
+	b, err := json.Marshal(unsafeBytes2String(in))
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(b)
+}
+
+func formatBase64(w io.Writer, in []byte) (int, error) {
+	enc := base64.StdEncoding
+	buf := make([]byte, enc.EncodedLen(len(in)))
+	enc.Encode(buf, in)
+	return w.Write(buf)
+}
+
+func formatTrim(w io.Writer, in []byte) (int, error) {
+	return w.Write(bytes.TrimSpace(in))
+}
+
+func formatUpper(w io.Writer, in []byte) (int, error) {
+	return io.WriteString(w, strings.ToUpper(unsafeBytes2String(in)))
+}
+
+func formatLower(w io.Writer, in []byte) (int, error) {
+	return io.WriteString(w, strings.ToLower(unsafeBytes2String(in)))
+}
+
+// SetFormatters overrides the FormatterMap used to resolve the formatter
+// chain of tags parsed after pipe syntax has been enabled via
+// EnablePipeSeparator. Call it before EnablePipeSeparator, or call
+// EnablePipeSeparator again afterwards, so the new map is picked up while
+// re-parsing the template.
+func (t *Template) SetFormatters(m FormatterMap) {
+	t.formatters = m
+}
+
+// EnablePipeSeparator turns on formatter-pipeline parsing for tags such as
+// "{{ref|url}}" or "{{user.name|html|upper}}", using sep to separate the
+// tag name from its formatter chain, and re-parses the template so the
+// change takes effect immediately.
+//
+// Pipeline parsing is opt-in: until EnablePipeSeparator is called, tags
+// containing a literal sep byte keep their current meaning, i.e. the whole
+// tag content, separator included, is used as the tag name.
+func (t *Template) EnablePipeSeparator(sep byte) error {
+	t.pipeSep = sep
+	return t.Reset(t.template, t.startTag, t.endTag)
+}
+
+// parseTagPipe splits tag on t.pipeSep, if enabled, into a bare tag name and
+// a chain of Formatters resolved from t.formatters (or DefaultFormatters).
+//
+// It returns the tag unchanged and a nil chain when pipe syntax is disabled
+// or tag doesn't contain the separator.
+func (t *Template) parseTagPipe(tag string) (string, []Formatter, error) {
+	if t.pipeSep == 0 {
+		return tag, nil, nil
+	}
+	if strings.IndexByte(tag, t.pipeSep) < 0 {
+		return tag, nil, nil
+	}
+
+	parts := strings.Split(tag, string(t.pipeSep))
+	name := strings.TrimSpace(parts[0])
+
+	fm := t.formatters
+	if fm == nil {
+		fm = DefaultFormatters
+	}
+
+	pipe := make([]Formatter, 0, len(parts)-1)
+	for _, fName := range parts[1:] {
+		fName = strings.TrimSpace(fName)
+		fn, ok := fm[fName]
+		if !ok {
+			return "", nil, fmt.Errorf("tag %q: unknown formatter %q: %w", name, fName, ErrUnknownFormatter)
+		}
+		pipe = append(pipe, fn)
+	}
+	return name, pipe, nil
+}
+
+// execPipe runs base through the chain of formatters in pipe, in order, and
+// writes the final result to w. It returns w.Write(base) unchanged when
+// pipe is empty.
+func execPipe(w io.Writer, pipe []Formatter, base []byte) (int, error) {
+	if len(pipe) == 0 {
+		return w.Write(base)
+	}
+
+	cur := byteBufferPool.Get()
+	next := byteBufferPool.Get()
+	defer func() {
+		byteBufferPool.Put(cur)
+		byteBufferPool.Put(next)
+	}()
+
+	in := base
+	for _, fn := range pipe {
+		next.Reset()
+		if _, err := fn(next, in); err != nil {
+			return 0, err
+		}
+		cur, next = next, cur
+		in = cur.B
+	}
+	return w.Write(in)
+}
@@ -0,0 +1,57 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestFormatterChainOrder(t *testing.T) {
+	tpl := New("hello, {{name|html|upper}}!", "{{", "}}")
+	if err := tpl.EnablePipeSeparator(DefaultPipeSeparator); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	m := map[string]interface{}{"name": []byte("<world>")}
+	s, err := tpl.ExecuteString(m)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// "html" escapes "<"/">" before "upper" uppercases the whole value, so
+	// the chain runs left to right rather than right to left.
+	expected := "hello, &LT;WORLD&GT;!"
+	if s != expected {
+		t.Fatalf("unexpected result\n%q\nExpected\n%q\n", s, expected)
+	}
+}
+
+func TestFormatterUnknownNameAtReset(t *testing.T) {
+	tpl := &Template{}
+	if err := tpl.Reset("{{name|bogus}}", "{{", "}}"); err != nil {
+		t.Fatalf("unexpected error before formatters are enabled: %s", err)
+	}
+
+	err := tpl.EnablePipeSeparator(DefaultPipeSeparator)
+	if err == nil {
+		t.Fatal("expected an error for an unknown formatter name")
+	}
+	if !errors.Is(err, ErrUnknownFormatter) {
+		t.Fatalf("expected ErrUnknownFormatter, got %v", err)
+	}
+}
+
+func TestFormatterOptInLeavesLiteralPipeAlone(t *testing.T) {
+	tpl := New("{{a|b}}", "{{", "}}")
+
+	var buf bytes.Buffer
+	m := map[string]interface{}{"a|b": []byte("literal")}
+	if _, err := tpl.Execute(&buf, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := "literal"
+	if buf.String() != expected {
+		t.Fatalf("unexpected result\n%q\nExpected\n%q\n", buf.String(), expected)
+	}
+}
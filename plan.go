@@ -0,0 +1,184 @@
+package fasttemplate
+
+import (
+	"io"
+	"net"
+	"unsafe"
+)
+
+// planStep is one entry of a Template's precompiled execution plan: a run
+// of static text immediately followed by the tag at tagIndex, or by no tag
+// at all (tagIndex == -1) for the trailing text after the last tag.
+//
+// text is kept as a raw pointer/length pair, rather than a []byte, so the
+// plan itself is a flat, allocation-free slice that execPlan can walk
+// without touching the backing texts slice.
+type planStep struct {
+	textPtr  unsafe.Pointer
+	textLen  int
+	tagIndex int32
+}
+
+func (s planStep) text() []byte {
+	if s.textLen == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(s.textPtr), s.textLen)
+}
+
+// BuffersWriter is implemented by writers that can flush a batch of byte
+// slices in a single call, e.g. a writev-backed net.Conn wrapper. When w
+// passed to ExecuteBuffers implements it, and none of the template's
+// resolved tag values need per-tag processing, every static text segment
+// and resolved tag value is queued into one net.Buffers and written in a
+// single call instead of one w.Write per segment.
+type BuffersWriter interface {
+	WriteBuffers(bufs net.Buffers) (int64, error)
+}
+
+// resolveTags looks up each of t's distinct tag names in m exactly once,
+// returning the results indexed by planStep.tagIndex.
+func (t *Template) resolveTags(m map[string]interface{}) []interface{} {
+	if len(t.uniqueTags) == 0 {
+		return nil
+	}
+	resolved := make([]interface{}, len(t.uniqueTags))
+	for i, name := range t.uniqueTags {
+		resolved[i] = m[name]
+	}
+	return resolved
+}
+
+// execPlan writes t.plan to w, substituting each step's tag with its
+// already-resolved value from resolved.
+func (t *Template) execPlan(w io.Writer, resolved []interface{}) (int64, error) {
+	if len(t.plan) == 0 {
+		ni, err := w.Write(unsafeString2Bytes(t.template))
+		return int64(ni), err
+	}
+
+	var nn int64
+	for i, step := range t.plan {
+		if step.textLen > 0 {
+			ni, err := w.Write(step.text())
+			nn += int64(ni)
+			if err != nil {
+				return nn, err
+			}
+		}
+		if step.tagIndex < 0 {
+			continue
+		}
+
+		var pipe []Formatter
+		if i < len(t.pipes) {
+			pipe = t.pipes[i]
+		}
+		ni, err := writeResolvedTag(w, resolved[step.tagIndex], t.uniqueTags[step.tagIndex], pipe)
+		nn += int64(ni)
+		if err != nil {
+			return nn, err
+		}
+	}
+	return nn, nil
+}
+
+// writeResolvedTag writes an already-resolved tag value to w, the same way
+// stdTagFunc would for the []byte/string/TagFunc value types, running it
+// through pipe first when non-empty.
+func writeResolvedTag(w io.Writer, v interface{}, tag string, pipe []Formatter) (int, error) {
+	if v == nil {
+		return 0, nil
+	}
+	if len(pipe) == 0 {
+		switch value := v.(type) {
+		case []byte:
+			return w.Write(value)
+		case string:
+			return w.Write(unsafeString2Bytes(value))
+		case TagFunc:
+			return value(w, tag)
+		default:
+			return 0, ErrInvalidTag
+		}
+	}
+
+	bb := byteBufferPool.Get()
+	defer byteBufferPool.Put(bb)
+	switch value := v.(type) {
+	case []byte:
+		bb.Write(value)
+	case string:
+		bb.Write(unsafeString2Bytes(value))
+	case TagFunc:
+		if _, err := value(bb, tag); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, ErrInvalidTag
+	}
+	return execPipe(w, pipe, bb.B)
+}
+
+// ExecuteBuffers substitutes template tags (placeholders) with the
+// corresponding values from the map m and writes the result to w, the same
+// as Execute, but additionally batches every static text segment and
+// resolved tag value into a single net.Buffers write when w implements
+// BuffersWriter and none of the resolved values is a TagFunc or routed
+// through a formatter pipe.
+//
+// Returns the number of bytes written to w.
+func (t *Template) ExecuteBuffers(w io.Writer, m map[string]interface{}) (int64, error) {
+	resolved := t.resolveTags(m)
+
+	bw, canBatch := w.(BuffersWriter)
+	if canBatch && t.hasPipes {
+		canBatch = false
+	}
+	if canBatch {
+		for _, v := range resolved {
+			switch v.(type) {
+			case nil, []byte, string:
+			default:
+				canBatch = false
+			}
+			if !canBatch {
+				break
+			}
+		}
+	}
+	if canBatch {
+		return t.execPlanBuffers(bw, resolved)
+	}
+	return t.execPlan(w, resolved)
+}
+
+// execPlanBuffers is the BuffersWriter fast path of ExecuteBuffers: it
+// requires every resolved tag value to already be []byte/string/nil, so it
+// never needs to call back into user code while building bufs.
+func (t *Template) execPlanBuffers(w BuffersWriter, resolved []interface{}) (int64, error) {
+	if len(t.plan) == 0 {
+		return w.WriteBuffers(net.Buffers{unsafeString2Bytes(t.template)})
+	}
+
+	bufs := make(net.Buffers, 0, 2*len(t.plan))
+	for _, step := range t.plan {
+		if step.textLen > 0 {
+			bufs = append(bufs, step.text())
+		}
+		if step.tagIndex < 0 {
+			continue
+		}
+		switch value := resolved[step.tagIndex].(type) {
+		case []byte:
+			if len(value) > 0 {
+				bufs = append(bufs, value)
+			}
+		case string:
+			if len(value) > 0 {
+				bufs = append(bufs, unsafeString2Bytes(value))
+			}
+		}
+	}
+	return w.WriteBuffers(bufs)
+}
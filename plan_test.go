@@ -0,0 +1,112 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestExecuteResolvesRepeatedTagOnce(t *testing.T) {
+	tpl := &Template{}
+	if err := tpl.Reset("hello {{a}} {{b}} {{a}}!", "{{", "}}"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(tpl.uniqueTags) != 2 {
+		t.Fatalf("expected 2 unique tags, got %d: %v", len(tpl.uniqueTags), tpl.uniqueTags)
+	}
+
+	var buf bytes.Buffer
+	m := map[string]interface{}{"a": []byte("A"), "b": []byte("B")}
+	if _, err := tpl.Execute(&buf, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := "hello A B A!"
+	if buf.String() != expected {
+		t.Fatalf("unexpected result\n%q\nExpected\n%q\n", buf.String(), expected)
+	}
+}
+
+func TestExecutePlanSurvivesTemplateReuse(t *testing.T) {
+	tpl := &Template{}
+	if err := tpl.Reset("hi {{name}}", "{{", "}}"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var buf bytes.Buffer
+	if _, err := tpl.Execute(&buf, map[string]interface{}{"name": []byte("x")}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if buf.String() != "hi x" {
+		t.Fatalf("unexpected result %q", buf.String())
+	}
+
+	// Reset onto a tag-free template must not leave stale plan entries from
+	// the previous, tagged template lying around.
+	buf.Reset()
+	if err := tpl.Reset("plain text", "{{", "}}"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := tpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if buf.String() != "plain text" {
+		t.Fatalf("unexpected result %q", buf.String())
+	}
+}
+
+// buffersSpy adapts a bytes.Buffer into BuffersWriter and records whether
+// the net.Buffers fast path was actually used.
+type buffersSpy struct {
+	bytes.Buffer
+	called bool
+}
+
+func (w *buffersSpy) WriteBuffers(bufs net.Buffers) (int64, error) {
+	w.called = true
+	return bufs.WriteTo(&w.Buffer)
+}
+
+func TestExecuteBuffersUsesWritevForPlainValues(t *testing.T) {
+	tpl := &Template{}
+	if err := tpl.Reset("a={{a}};b={{b}};a={{a}}", "{{", "}}"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	w := &buffersSpy{}
+	m := map[string]interface{}{"a": []byte("1"), "b": "2"}
+	if _, err := tpl.ExecuteBuffers(w, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !w.called {
+		t.Fatal("expected ExecuteBuffers to use the BuffersWriter fast path")
+	}
+
+	expected := "a=1;b=2;a=1"
+	if w.Buffer.String() != expected {
+		t.Fatalf("unexpected result\n%q\nExpected\n%q\n", w.Buffer.String(), expected)
+	}
+}
+
+func TestExecuteBuffersFallsBackForTagFunc(t *testing.T) {
+	tpl := &Template{}
+	if err := tpl.Reset("v={{v}}", "{{", "}}"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	w := &buffersSpy{}
+	m := map[string]interface{}{
+		"v": TagFunc(func(w io.Writer, tag string) (int, error) { return w.Write([]byte("dyn")) }),
+	}
+	if _, err := tpl.ExecuteBuffers(w, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if w.called {
+		t.Fatal("did not expect the BuffersWriter fast path with a TagFunc value")
+	}
+
+	expected := "v=dyn"
+	if w.Buffer.String() != expected {
+		t.Fatalf("unexpected result\n%q\nExpected\n%q\n", w.Buffer.String(), expected)
+	}
+}
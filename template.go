@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"unsafe"
 
 	"github.com/valyala/bytebufferpool"
 )
@@ -125,7 +126,15 @@ type Template struct {
 
 	texts          [][]byte
 	tags           []string
+	pipes          [][]Formatter
+	pipeSep        byte
+	formatters     FormatterMap
 	byteBufferPool bytebufferpool.Pool
+
+	plan       []planStep
+	uniqueTags []string
+	tagIdx     map[string]int32
+	hasPipes   bool
 }
 
 // New parses the given template using the given startTag and endTag
@@ -180,6 +189,10 @@ func (t *Template) Reset(template, startTag, endTag string) error {
 	t.endTag = endTag
 	t.texts = t.texts[:0]
 	t.tags = t.tags[:0]
+	t.pipes = t.pipes[:0]
+	t.plan = t.plan[:0]
+	t.uniqueTags = t.uniqueTags[:0]
+	t.hasPipes = false
 
 	if len(startTag) == 0 {
 		return ErrEmptyStartTag
@@ -203,6 +216,9 @@ func (t *Template) Reset(template, startTag, endTag string) error {
 	if tagsCount > cap(t.tags) {
 		t.tags = make([]string, 0, tagsCount)
 	}
+	if tagsCount > cap(t.pipes) {
+		t.pipes = make([][]Formatter, 0, tagsCount)
+	}
 
 	for {
 		n := bytes.Index(templateBytes, startTagBytes)
@@ -246,12 +262,50 @@ func (t *Template) Reset(template, startTag, endTag string) error {
 		}
 
 		tag := append(missingTag, templateBytes[:n]...)
-		t.tags = append(t.tags, unsafeBytes2String(bytes.TrimSpace(tag)))
+		tagName, pipe, err := t.parseTagPipe(unsafeBytes2String(bytes.TrimSpace(tag)))
+		if err != nil {
+			return err
+		}
+		t.tags = append(t.tags, tagName)
+		t.pipes = append(t.pipes, pipe)
 		templateBytes = templateBytes[n+len(endTagBytes):]
 	}
+	t.buildPlan()
 	return nil
 }
 
+// buildPlan flattens t.texts/t.tags into t.plan, a single slice execPlan
+// can walk in one tight loop, and deduplicates tag names into t.uniqueTags
+// so Execute/ExecuteBuffers can resolve each distinct tag against a map
+// only once per call instead of once per occurrence.
+func (t *Template) buildPlan() {
+	t.plan = t.plan[:0]
+	t.uniqueTags = t.uniqueTags[:0]
+	t.tagIdx = make(map[string]int32, len(t.tags))
+	t.hasPipes = false
+
+	for i, txt := range t.texts {
+		step := planStep{textLen: len(txt), tagIndex: -1}
+		if len(txt) > 0 {
+			step.textPtr = unsafe.Pointer(&txt[0])
+		}
+		if i < len(t.tags) {
+			name := t.tags[i]
+			idx, ok := t.tagIdx[name]
+			if !ok {
+				idx = int32(len(t.uniqueTags))
+				t.uniqueTags = append(t.uniqueTags, name)
+				t.tagIdx[name] = idx
+			}
+			step.tagIndex = idx
+			if len(t.pipes[i]) > 0 {
+				t.hasPipes = true
+			}
+		}
+		t.plan = append(t.plan, step)
+	}
+}
+
 // ExecuteFunc calls f on each template tag (placeholder) occurrence.
 //
 // Returns the number of bytes written to w.
@@ -274,7 +328,18 @@ func (t *Template) ExecuteFunc(w io.Writer, f TagFunc) (int64, error) {
 			return nn, err
 		}
 
-		ni, err = f(w, t.tags[i])
+		if len(t.pipes[i]) == 0 {
+			ni, err = f(w, t.tags[i])
+		} else {
+			ni = 0
+			bb := byteBufferPool.Get()
+			_, err = f(bb, t.tags[i])
+			if err == nil {
+				ni, err = execPipe(w, t.pipes[i], bb.B)
+			}
+			bb.Reset()
+			byteBufferPool.Put(bb)
+		}
 		nn += int64(ni)
 		if err != nil {
 			return nn, err
@@ -294,8 +359,19 @@ func (t *Template) ExecuteFunc(w io.Writer, f TagFunc) (int64, error) {
 //   * TagFunc - flexible value type
 //
 // Returns the number of bytes written to w.
+//
+// Execute resolves each distinct tag name against m only once per call,
+// via the plan built by Reset, so templates with repeated tags such as
+// "{{uid}}...{{uid}}" pay the map lookup once instead of per occurrence.
+//
+// Execute defers to ExecuteBuffers, so it also gets the batched net.Buffers
+// write when w implements BuffersWriter and the resolved tag values allow it;
+// callers who want to guarantee the plain per-segment w.Write path, e.g.
+// because w's WriteBuffers is more expensive than separate Writes, can call
+// execPlan's behavior directly by passing a writer that doesn't implement
+// BuffersWriter.
 func (t *Template) Execute(w io.Writer, m map[string]interface{}) (int64, error) {
-	return t.ExecuteFunc(w, func(w io.Writer, tag string) (int, error) { return stdTagFunc(w, tag, m) })
+	return t.ExecuteBuffers(w, m)
 }
 
 // ExecuteFuncString calls f on each template tag (placeholder) occurrence
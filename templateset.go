@@ -0,0 +1,394 @@
+package fasttemplate
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TemplateSet holds a group of named templates that may reference each
+// other via the "extends", "block" and "include" directives, bringing
+// pongo2/Django-style template composition on top of fasttemplate's usual
+// tag substitution.
+//
+//	{{extends "base"}}           - must open the template, before any
+//	                                text or other tag; makes it inherit
+//	                                base's skeleton and block defaults.
+//	{{block "name"}}...{{endblock}} - a named, overridable section.
+//	{{include "partial"}}        - recursively executes another named
+//	                                template in place, using the same
+//	                                substitution map.
+//
+// A TemplateSet is not safe for concurrent Parse calls, but Execute may be
+// called concurrently once all the templates it needs have been parsed.
+type TemplateSet struct {
+	startTag string
+	endTag   string
+	loader   func(name string) (string, error)
+
+	templates map[string]*parsedTemplate
+}
+
+// NewTemplateSet creates an empty TemplateSet using startTag and endTag as
+// the tag delimiters for every template parsed into it.
+func NewTemplateSet(startTag, endTag string) *TemplateSet {
+	return &TemplateSet{
+		startTag:  startTag,
+		endTag:    endTag,
+		templates: make(map[string]*parsedTemplate),
+	}
+}
+
+// SetLoader registers a callback used to lazily fetch the source of a
+// template referenced by "extends" or "include" but not yet passed to
+// Parse. It is consulted by Parse and Execute whenever a referenced name
+// isn't already known.
+func (ts *TemplateSet) SetLoader(loader func(name string) (string, error)) {
+	ts.loader = loader
+}
+
+// Parse parses template and registers it under name, replacing any
+// previous template registered under the same name.
+//
+// If template starts with {{extends "parent"}}, parent must already be
+// registered via Parse or be resolvable through the loader set with
+// SetLoader; Parse follows the resulting extends chain eagerly so that
+// cycles are reported here rather than at Execute time.
+func (ts *TemplateSet) Parse(name, template string) error {
+	if len(ts.startTag) == 0 {
+		return ErrEmptyStartTag
+	}
+	if len(ts.endTag) == 0 {
+		return ErrEmptyEndTag
+	}
+
+	toks, err := tokenizeDirectives(template, ts.startTag, ts.endTag)
+	if err != nil {
+		return err
+	}
+
+	extends := ""
+	if len(toks) > 0 && toks[0].isTag {
+		if kind, arg, ok := parseDirective(toks[0].text); ok && kind == "extends" {
+			extends = arg
+			toks = toks[1:]
+		}
+	}
+
+	ops, _, err := parseOps(toks, "")
+	if err != nil {
+		return fmt.Errorf("template %q: %w", name, err)
+	}
+
+	prev := ts.templates[name]
+	ts.templates[name] = &parsedTemplate{name: name, extends: extends, ops: ops}
+
+	if extends != "" {
+		if err := ts.checkExtendsCycle(name); err != nil {
+			ts.templates[name] = prev
+			return err
+		}
+	}
+	if err := ts.checkIncludeCycle(name); err != nil {
+		ts.templates[name] = prev
+		return err
+	}
+	return nil
+}
+
+// Execute renders the template registered under name into w, substituting
+// tags from m the same way Template.Execute does, and resolving any
+// "extends"/"block"/"include" directives it references.
+//
+// Returns the number of bytes written to w.
+func (ts *TemplateSet) Execute(name string, w io.Writer, m map[string]interface{}) (int64, error) {
+	pt, err := ts.resolve(name)
+	if err != nil {
+		return 0, err
+	}
+
+	chain := []*parsedTemplate{pt}
+	seen := map[string]bool{name: true}
+	for chain[len(chain)-1].extends != "" {
+		parentName := chain[len(chain)-1].extends
+		if seen[parentName] {
+			return 0, &CycleError{Name: parentName}
+		}
+		seen[parentName] = true
+		parent, err := ts.resolve(parentName)
+		if err != nil {
+			return 0, err
+		}
+		chain = append(chain, parent)
+	}
+
+	overrides := make(map[string][]op)
+	for i := len(chain) - 1; i >= 0; i-- {
+		collectBlocks(chain[i].ops, overrides)
+	}
+
+	root := chain[len(chain)-1]
+	return ts.execOps(root.ops, w, m, overrides)
+}
+
+// resolve returns the parsed template registered under name, parsing it on
+// demand through the configured loader if it isn't known yet.
+func (ts *TemplateSet) resolve(name string) (*parsedTemplate, error) {
+	if pt, ok := ts.templates[name]; ok {
+		return pt, nil
+	}
+	if ts.loader == nil {
+		return nil, fmt.Errorf("fasttemplate: template %q isn't registered and no loader is set", name)
+	}
+	src, err := ts.loader(name)
+	if err != nil {
+		return nil, fmt.Errorf("fasttemplate: cannot load template %q: %w", name, err)
+	}
+	if err := ts.Parse(name, src); err != nil {
+		return nil, err
+	}
+	return ts.templates[name], nil
+}
+
+func (ts *TemplateSet) checkExtendsCycle(name string) error {
+	seen := map[string]bool{name: true}
+	cur, err := ts.resolve(name)
+	if err != nil {
+		return err
+	}
+	for cur.extends != "" {
+		if seen[cur.extends] {
+			return &CycleError{Name: cur.extends}
+		}
+		seen[cur.extends] = true
+		cur, err = ts.resolve(cur.extends)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ts *TemplateSet) checkIncludeCycle(name string) error {
+	return ts.walkIncludes(name, name, map[string]bool{name: true})
+}
+
+func (ts *TemplateSet) walkIncludes(origin, name string, seen map[string]bool) error {
+	pt, err := ts.resolve(name)
+	if err != nil {
+		return err
+	}
+	for _, inc := range includeNames(pt.ops) {
+		if inc == origin {
+			return &CycleError{Name: inc}
+		}
+		if seen[inc] {
+			continue
+		}
+		seen[inc] = true
+		if err := ts.walkIncludes(origin, inc, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execOps renders ops to w, substituting each opBlock's body with its
+// override from overrides when present.
+func (ts *TemplateSet) execOps(ops []op, w io.Writer, m map[string]interface{}, overrides map[string][]op) (int64, error) {
+	var nn int64
+	for _, o := range ops {
+		var (
+			ni  int
+			n64 int64
+			err error
+		)
+		switch o.kind {
+		case opText:
+			ni, err = w.Write(o.text)
+		case opTag:
+			ni, err = stdTagFunc(w, o.tag, m)
+		case opInclude:
+			n64, err = ts.Execute(o.tag, w, m)
+			ni = int(n64)
+		case opBlock:
+			body := o.body
+			if ov, ok := overrides[o.tag]; ok {
+				body = ov
+			}
+			n64, err = ts.execOps(body, w, m, overrides)
+			ni = int(n64)
+		}
+		nn += int64(ni)
+		if err != nil {
+			return nn, err
+		}
+	}
+	return nn, nil
+}
+
+// collectBlocks records the body of every opBlock in ops, including ones
+// nested inside other blocks, into out, keyed by block name.
+func collectBlocks(ops []op, out map[string][]op) {
+	for _, o := range ops {
+		if o.kind == opBlock {
+			out[o.tag] = o.body
+			collectBlocks(o.body, out)
+		}
+	}
+}
+
+// includeNames returns the names referenced by every opInclude in ops,
+// including ones nested inside blocks.
+func includeNames(ops []op) []string {
+	var names []string
+	for _, o := range ops {
+		switch o.kind {
+		case opInclude:
+			names = append(names, o.tag)
+		case opBlock:
+			names = append(names, includeNames(o.body)...)
+		}
+	}
+	return names
+}
+
+// parsedTemplate is the result of parsing a single TemplateSet entry: its
+// optional parent name and the sequence of ops making up its body.
+type parsedTemplate struct {
+	name    string
+	extends string
+	ops     []op
+}
+
+type opKind int
+
+const (
+	opText opKind = iota
+	opTag
+	opInclude
+	opBlock
+)
+
+// op is a single step of a parsed template's execution: literal text, a
+// plain substitution tag, an include of another named template, or a
+// block with a default body that Execute may substitute with an override.
+type op struct {
+	kind opKind
+	text []byte
+	tag  string
+	body []op
+}
+
+type directiveToken struct {
+	isTag bool
+	text  string
+}
+
+// tokenizeDirectives splits template on startTag/endTag into alternating
+// literal text and trimmed tag-content tokens.
+func tokenizeDirectives(template, startTag, endTag string) ([]directiveToken, error) {
+	var toks []directiveToken
+	s := template
+	for {
+		n := strings.Index(s, startTag)
+		if n < 0 {
+			if s != "" {
+				toks = append(toks, directiveToken{text: s})
+			}
+			return toks, nil
+		}
+		if n > 0 {
+			toks = append(toks, directiveToken{text: s[:n]})
+		}
+		s = s[n+len(startTag):]
+
+		m := strings.Index(s, endTag)
+		if m < 0 {
+			return nil, fmt.Errorf("cannot find end tag=%q in template=%q", endTag, template)
+		}
+		toks = append(toks, directiveToken{isTag: true, text: strings.TrimSpace(s[:m])})
+		s = s[m+len(endTag):]
+	}
+}
+
+// parseDirective recognizes "extends \"x\"", "block \"x\"", "include \"x\""
+// and "endblock" tag contents, returning the directive keyword and its
+// (unquoted) argument.
+func parseDirective(tagContent string) (kind, arg string, ok bool) {
+	content := strings.TrimSpace(tagContent)
+	if content == "endblock" {
+		return "endblock", "", true
+	}
+	for _, kw := range []string{"extends", "block", "include"} {
+		if !strings.HasPrefix(content, kw) {
+			continue
+		}
+		rest := strings.TrimSpace(content[len(kw):])
+		name, unquoted := unquoteDirectiveArg(rest)
+		if !unquoted {
+			continue
+		}
+		return kw, name, true
+	}
+	return "", "", false
+}
+
+func unquoteDirectiveArg(s string) (string, bool) {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1], true
+	}
+	return "", false
+}
+
+// parseOps turns a flat token stream into an op tree, recursing into
+// parseOps again at each "block" directive until its matching "endblock"
+// so nested blocks come back as nested ops.
+//
+// blockName is the name of the enclosing {{block "blockName"}} when
+// parseOps is recursing into that block's body, or "" for the top-level
+// call, so both a stray {{endblock}} and a {{block}} missing its closing
+// {{endblock}} can be reported as parse errors instead of silently
+// truncating the rest of the template.
+func parseOps(toks []directiveToken, blockName string) ([]op, []directiveToken, error) {
+	var ops []op
+	for len(toks) > 0 {
+		tok := toks[0]
+		if !tok.isTag {
+			ops = append(ops, op{kind: opText, text: []byte(tok.text)})
+			toks = toks[1:]
+			continue
+		}
+
+		kind, arg, ok := parseDirective(tok.text)
+		if !ok {
+			ops = append(ops, op{kind: opTag, tag: tok.text})
+			toks = toks[1:]
+			continue
+		}
+
+		switch kind {
+		case "endblock":
+			if blockName == "" {
+				return nil, nil, fmt.Errorf("{{endblock}} without a matching {{block}}")
+			}
+			return ops, toks[1:], nil
+		case "block":
+			body, rest, err := parseOps(toks[1:], arg)
+			if err != nil {
+				return nil, nil, err
+			}
+			ops = append(ops, op{kind: opBlock, tag: arg, body: body})
+			toks = rest
+		case "include":
+			ops = append(ops, op{kind: opInclude, tag: arg})
+			toks = toks[1:]
+		case "extends":
+			return nil, nil, fmt.Errorf("{{extends %q}} must be at the very start of the template", arg)
+		}
+	}
+	if blockName != "" {
+		return nil, nil, fmt.Errorf("{{block %q}} is missing its closing {{endblock}}", blockName)
+	}
+	return ops, toks, nil
+}
@@ -0,0 +1,133 @@
+package fasttemplate
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestTemplateSetExtendsAndBlocks(t *testing.T) {
+	ts := NewTemplateSet("{{", "}}")
+	base := `<html>{{block "title"}}default title{{endblock}} body {{block "content"}}default content{{endblock}}</html>`
+	if err := ts.Parse("base", base); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	child := `{{extends "base"}}{{block "content"}}hello {{name}}{{endblock}}`
+	if err := ts.Parse("child", child); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	m := map[string]interface{}{"name": []byte("world")}
+	if _, err := ts.Execute("child", &buf, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := `<html>default title body hello world</html>`
+	if buf.String() != expected {
+		t.Fatalf("unexpected result\n%q\nExpected\n%q\n", buf.String(), expected)
+	}
+}
+
+func TestTemplateSetInclude(t *testing.T) {
+	ts := NewTemplateSet("{{", "}}")
+	if err := ts.Parse("partial", "hi {{name}}"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := ts.Parse("main", `before {{include "partial"}} after`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	m := map[string]interface{}{"name": []byte("bob")}
+	if _, err := ts.Execute("main", &buf, m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := "before hi bob after"
+	if buf.String() != expected {
+		t.Fatalf("unexpected result\n%q\nExpected\n%q\n", buf.String(), expected)
+	}
+}
+
+func TestTemplateSetLazyLoader(t *testing.T) {
+	ts := NewTemplateSet("{{", "}}")
+	sources := map[string]string{
+		"base": `<p>{{block "x"}}default{{endblock}}</p>`,
+	}
+	ts.SetLoader(func(name string) (string, error) {
+		src, ok := sources[name]
+		if !ok {
+			return "", errors.New("template not found")
+		}
+		return src, nil
+	})
+
+	if err := ts.Parse("child", `{{extends "base"}}{{block "x"}}override{{endblock}}`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := ts.Execute("child", &buf, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := "<p>override</p>"
+	if buf.String() != expected {
+		t.Fatalf("unexpected result\n%q\nExpected\n%q\n", buf.String(), expected)
+	}
+}
+
+func TestTemplateSetExtendsCycle(t *testing.T) {
+	ts := NewTemplateSet("{{", "}}")
+	sources := map[string]string{
+		"a": `{{extends "b"}}`,
+		"b": `{{extends "a"}}`,
+	}
+	ts.SetLoader(func(name string) (string, error) {
+		src, ok := sources[name]
+		if !ok {
+			return "", errors.New("template not found")
+		}
+		return src, nil
+	})
+
+	var cycleErr *CycleError
+	if err := ts.Parse("a", sources["a"]); !errors.As(err, &cycleErr) {
+		t.Fatalf("expected a *CycleError, got %v", err)
+	}
+}
+
+func TestTemplateSetIncludeCycle(t *testing.T) {
+	ts := NewTemplateSet("{{", "}}")
+	sources := map[string]string{
+		"a": `{{include "b"}}`,
+		"b": `{{include "a"}}`,
+	}
+	ts.SetLoader(func(name string) (string, error) {
+		src, ok := sources[name]
+		if !ok {
+			return "", errors.New("template not found")
+		}
+		return src, nil
+	})
+
+	var cycleErr *CycleError
+	if err := ts.Parse("a", sources["a"]); !errors.As(err, &cycleErr) {
+		t.Fatalf("expected a *CycleError, got %v", err)
+	}
+}
+
+func TestTemplateSetParseStrayEndblock(t *testing.T) {
+	ts := NewTemplateSet("{{", "}}")
+	if err := ts.Parse("a", "A{{endblock}}B"); err == nil {
+		t.Fatal("expected an error for a stray {{endblock}} with no matching {{block}}")
+	}
+}
+
+func TestTemplateSetParseUnterminatedBlock(t *testing.T) {
+	ts := NewTemplateSet("{{", "}}")
+	if err := ts.Parse("a", `A{{block "x"}}B`); err == nil {
+		t.Fatal("expected an error for a {{block}} missing its closing {{endblock}}")
+	}
+}
@@ -3,6 +3,7 @@ package fasttemplate
 import (
 	"bytes"
 	"io"
+	"net"
 	"net/url"
 	"strings"
 	"testing"
@@ -118,6 +119,39 @@ func BenchmarkFastTemplateExecute(b *testing.B) {
 	})
 }
 
+// buffersWriter adapts a bytes.Buffer into fasttemplate.BuffersWriter so
+// BenchmarkFastTemplateExecuteBuffers can exercise the net.Buffers fast
+// path without a real writev-capable net.Conn.
+type buffersWriter struct {
+	bytes.Buffer
+}
+
+func (w *buffersWriter) WriteBuffers(bufs net.Buffers) (int64, error) {
+	return bufs.WriteTo(&w.Buffer)
+}
+
+func BenchmarkFastTemplateExecuteBuffers(b *testing.B) {
+	t, err := NewTemplate(source, "{{", "}}")
+	if err != nil {
+		b.Fatalf("error in template: %s", err)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var w buffersWriter
+		for pb.Next() {
+			if _, err := t.ExecuteBuffers(&w, m); err != nil {
+				b.Fatalf("unexpected error: %s", err)
+			}
+			x := w.Bytes()
+			if !bytes.Equal(x, resultBytes) {
+				b.Fatalf("unexpected result\n%q\nExpected\n%q\n", x, resultBytes)
+			}
+			w.Reset()
+		}
+	})
+}
+
 func BenchmarkFastTemplateExecuteString(b *testing.B) {
 	t, err := NewTemplate(source, "{{", "}}")
 	if err != nil {
@@ -127,7 +161,10 @@ func BenchmarkFastTemplateExecuteString(b *testing.B) {
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			x := t.ExecuteString(m)
+			x, err := t.ExecuteString(m)
+			if err != nil {
+				b.Fatalf("unexpected error: %s", err)
+			}
 			if x != result {
 				b.Fatalf("unexpected result\n%q\nExpected\n%q\n", x, result)
 			}
@@ -145,7 +182,7 @@ func BenchmarkFastTemplateExecuteTagFunc(b *testing.B) {
 	for k, v := range m {
 		if k == "ref" {
 			vv := v.([]byte)
-			v = TagFunc(func(w io.Writer) (int, error) { return w.Write([]byte(url.QueryEscape(string(vv)))) })
+			v = TagFunc(func(w io.Writer, tag string) (int, error) { return w.Write([]byte(url.QueryEscape(string(vv)))) })
 		}
 		mm[k] = v
 	}
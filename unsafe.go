@@ -12,5 +12,10 @@ func unsafeBytes2String(b []byte) string {
 }
 
 func unsafeString2Bytes(s string) (b []byte) {
-	return *(*[]byte)(unsafe.Pointer(&s))
+	sh := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	bh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	bh.Data = sh.Data
+	bh.Len = sh.Len
+	bh.Cap = sh.Len
+	return b
 }